@@ -6,7 +6,10 @@ import "C"
 
 import (
 	"fmt"
+	"net"
 	"runtime"
+	"strings"
+	"sync"
 	"unsafe"
 
 	"github.com/gofrs/uuid"
@@ -25,12 +28,18 @@ const (
 
 // The Schema type holds the names and types of fields available to the router.
 type Schema struct {
-	s *C.Schema
+	s       *C.Schema
+	fields  map[string]struct{}
+	aliases map[string]string
 }
 
 // NewSchema creates a new empty Schema object
 func NewSchema() *Schema {
-	s := &Schema{s: C.schema_new()}
+	s := &Schema{
+		s:       C.schema_new(),
+		fields:  make(map[string]struct{}),
+		aliases: make(map[string]string),
+	}
 	runtime.SetFinalizer(s, (*Schema).Free)
 	return s
 }
@@ -48,11 +57,193 @@ func (s *Schema) AddField(field string, typ FieldType) {
 	defer C.free(unsafe.Pointer(fieldC))
 
 	C.schema_add_field(s.s, (*C.schar)(fieldC), uint32(typ))
+	s.fields[field] = struct{}{}
+}
+
+// AddFieldAlias registers oldName as a backwards-compatible alias for the
+// already-registered canonicalName field, so that ATC rule source passed to
+// Router.AddMatcher and field names passed to Context.AddValue may use
+// either name interchangeably. It returns an error if canonicalName has not
+// been added via AddField yet, or if oldName already names a field of its
+// own.
+func (s *Schema) AddFieldAlias(oldName, canonicalName string) error {
+	if _, ok := s.fields[oldName]; ok {
+		return fmt.Errorf("alias %q shadows an existing field", oldName)
+	}
+	if _, ok := s.fields[canonicalName]; !ok {
+		return fmt.Errorf("cannot alias to unregistered field %q", canonicalName)
+	}
+	s.aliases[oldName] = canonicalName
+	return nil
+}
+
+// resolveAlias returns the canonical field name for field, or field itself
+// if it is not an alias.
+func (s *Schema) resolveAlias(field string) string {
+	if canonical, ok := s.aliases[field]; ok {
+		return canonical
+	}
+	return field
+}
+
+// rewriteAliases rewrites every occurrence of a registered alias in atc
+// with its canonical field name, so that rule source written against old
+// field names keeps parsing after a schema rename. It is not a regex
+// substitution: atc is scanned a single time, string literals are copied
+// verbatim so an alias name that happens to appear inside a quoted value is
+// left alone, and only whole field-identifier tokens (runs of letters,
+// digits, '_' and '.') are looked up against the alias table, so an alias
+// can never match as a prefix of an unrelated, longer field name.
+func (s *Schema) rewriteAliases(atc string) string {
+	if len(s.aliases) == 0 {
+		return atc
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(atc); {
+		c := atc[i]
+		switch {
+		case c == '"':
+			start := i
+			i++
+			for i < len(atc) {
+				if atc[i] == '\\' && i+1 < len(atc) {
+					i += 2
+					continue
+				}
+				i++
+				if atc[i-1] == '"' {
+					break
+				}
+			}
+			out.WriteString(atc[start:i])
+		case isFieldIdentByte(c):
+			start := i
+			for i < len(atc) && isFieldIdentByte(atc[i]) {
+				i++
+			}
+			token := atc[start:i]
+			if canonical, ok := s.aliases[token]; ok {
+				out.WriteString(canonical)
+			} else {
+				out.WriteString(token)
+			}
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// isFieldIdentByte reports whether b can occur in an ATC field identifier
+// such as "http.path".
+func isFieldIdentByte(b byte) bool {
+	return b == '.' || b == '_' ||
+		(b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// ValidationErrorKind distinguishes the category of failure reported by
+// Schema.Validate. It is a best-effort classification, not a structured
+// signal from the Rust core — see the caveat on classifyValidationError.
+type ValidationErrorKind int
+
+const (
+	// ParseErrorKind is the default: classifyValidationError did not
+	// recognize the error message as field/type related, which in
+	// practice usually means the ATC source itself is malformed, but
+	// this is not guaranteed.
+	ParseErrorKind ValidationErrorKind = iota
+	// FieldErrorKind means the error message matched one of the known
+	// phrasings the Rust core uses for an unknown field or a type
+	// mismatch. As with ParseErrorKind, this is a guess, not a guarantee.
+	FieldErrorKind
+)
+
+// ValidationError is returned by Schema.Validate, carrying the Kind of
+// failure alongside the underlying message. Kind is best-effort (see
+// classifyValidationError); Message is always the authoritative error text
+// and should be preferred whenever Kind's guess isn't good enough.
+type ValidationError struct {
+	Kind    ValidationErrorKind
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Validate parses atc and type-checks it against the Schema, returning a
+// *ValidationError on failure. There is no parse/type-check-only primitive
+// in the Rust core, so under the hood this does exactly what callers do
+// today: build a throwaway Router bound to s and AddMatcher the rule into
+// it under a disposable UUID, discarding the Router afterwards. This is
+// still the primitive to reach for over hand-rolling that pattern: it adds
+// a best-effort ValidationErrorKind on top of AddMatcher's plain error
+// string, for UX like picking which hint to show on a dry-run admin
+// endpoint — see classifyValidationError for the limits of that guess.
+func (s *Schema) Validate(atc string) error {
+	router := NewRouter(s)
+	defer router.Free()
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	if err := router.AddMatcher(1, id, atc); err != nil {
+		return &ValidationError{Kind: classifyValidationError(err.Error()), Message: err.Error()}
+	}
+	return nil
+}
+
+// classifyValidationError makes a best-effort guess at a ValidationErrorKind
+// by pattern-matching the free-form error message AddMatcher returns. The
+// Rust core does not return a structured error code alongside that
+// message, so this is inherently a heuristic over message wording: it only
+// matches a narrow set of phrasings known today to indicate a field/type
+// problem, and will silently fall back to ParseErrorKind if upstream
+// wording changes or uses a phrasing not listed here. Do not rely on Kind
+// for anything beyond a best-effort UX hint; use Message for the
+// authoritative error.
+func classifyValidationError(msg string) ValidationErrorKind {
+	lower := strings.ToLower(msg)
+	fieldPhrases := []string{
+		"unknown field",
+		"undefined field",
+		"no such field",
+		"type mismatch",
+		"expected type",
+		"invalid type",
+	}
+	for _, phrase := range fieldPhrases {
+		if strings.Contains(lower, phrase) {
+			return FieldErrorKind
+		}
+	}
+	return ParseErrorKind
+}
+
+// MatcherID identifies a Matcher previously added to a Router by its
+// priority and UUID, as returned by Router.Matchers.
+type MatcherID struct {
+	Priority int
+	ID       uuid.UUID
 }
 
-// The Router type holds the Matcher rules.
+type matcherKey struct {
+	priority int
+	id       uuid.UUID
+}
+
+// The Router type holds the Matcher rules. A Router is safe for concurrent
+// use by Match; individual Context objects, including one returned by
+// NewContext, are not safe for concurrent use.
 type Router struct {
-	r *C.Router
+	r        *C.Router
+	schema   *Schema
+	matchers map[matcherKey]string
+	ctxPool  sync.Pool
 }
 
 // NewRouter creates a new empty Router object associated with
@@ -62,7 +253,7 @@ func NewRouter(s *Schema) *Router {
 		return nil
 	}
 
-	r := &Router{r: C.router_new(s.s)}
+	r := &Router{r: C.router_new(s.s), schema: s, matchers: make(map[matcherKey]string)}
 	runtime.SetFinalizer(r, (*Router).Free)
 	return r
 }
@@ -77,6 +268,8 @@ func (r *Router) Free() {
 // AddMatcher parses a new ATC rule and adds to the Router
 // under the given priority and ID.
 func (r *Router) AddMatcher(priority int, id uuid.UUID, atc string) error {
+	atc = r.schema.rewriteAliases(atc)
+
 	idC := C.CString(id.String())
 	defer C.free(unsafe.Pointer(idC))
 
@@ -89,5 +282,303 @@ func (r *Router) AddMatcher(priority int, id uuid.UUID, atc string) error {
 	if !ok {
 		return fmt.Errorf(string(errBuf[:errLen]))
 	}
+	r.matchers[matcherKey{priority, id}] = atc
+	return nil
+}
+
+// RemoveMatcher removes the Matcher previously added under priority and id.
+func (r *Router) RemoveMatcher(priority int, id uuid.UUID) error {
+	idC := C.CString(id.String())
+	defer C.free(unsafe.Pointer(idC))
+
+	if !C.router_remove_matcher(r.r, C.ulong(priority), (*C.schar)(idC)) {
+		return fmt.Errorf("no matcher found for priority %d and id %s", priority, id)
+	}
+	delete(r.matchers, matcherKey{priority, id})
+	return nil
+}
+
+// ReplaceMatcher removes the Matcher at priority/id, if any, and adds atc in
+// its place. If adding the new rule fails, the previous rule (if one
+// existed) is restored so the Router is left in its original state rather
+// than with a hole where id used to be.
+func (r *Router) ReplaceMatcher(priority int, id uuid.UUID, atc string) error {
+	key := matcherKey{priority, id}
+	oldAtc, existed := r.matchers[key]
+	if existed {
+		if err := r.RemoveMatcher(priority, id); err != nil {
+			return err
+		}
+	}
+
+	if err := r.AddMatcher(priority, id, atc); err != nil {
+		if existed {
+			_ = r.AddMatcher(priority, id, oldAtc)
+		}
+		return err
+	}
+	return nil
+}
+
+// Len returns the number of Matchers currently held by the Router.
+func (r *Router) Len() int {
+	return len(r.matchers)
+}
+
+// Matchers returns the priority/id pairs of every Matcher currently held by
+// the Router, so that callers can reconcile their desired state against it.
+func (r *Router) Matchers() []MatcherID {
+	ids := make([]MatcherID, 0, len(r.matchers))
+	for k := range r.matchers {
+		ids = append(ids, MatcherID{Priority: k.priority, ID: k.id})
+	}
+	return ids
+}
+
+// Execute runs the Router's matchers against the values populated in ctx,
+// returning whether a match was found along with the UUID of the matched
+// rule. ctx may be inspected afterwards with GetMatchedField and
+// GetMatchedCapture to retrieve the values and regex captures involved in
+// the match.
+func (r *Router) Execute(ctx *Context) (bool, uuid.UUID, error) {
+	matched := bool(C.router_execute(r.r, ctx.c))
+	if !matched {
+		ctx.matched = false
+		ctx.captures = nil
+		return false, uuid.Nil, nil
+	}
+
+	id, _, captures, err := contextGetResult(ctx, "")
+	if err != nil {
+		return true, uuid.Nil, err
+	}
+	ctx.matched = true
+	ctx.captures = captures
+	return true, id, nil
+}
+
+// contextGetResult wraps context_get_result, the single primitive the Rust
+// core exposes for reading back a match: it always fills the matched
+// Matcher's UUID and its named regex captures, and additionally resolves
+// the matched value of field when field is non-empty (an empty field name
+// skips that out-param). It returns intptr_t, the number of captures
+// written, so the Go side doesn't need a separate bool for "did this
+// match".
+func contextGetResult(ctx *Context, field string) (uuid.UUID, string, map[string]string, error) {
+	var fieldC *C.schar
+	if field != "" {
+		f := C.CString(field)
+		defer C.free(unsafe.Pointer(f))
+		fieldC = (*C.schar)(f)
+	}
+
+	uuidLen := C.ulong(64)
+	uuidBuf := [64]C.uchar{}
+	fieldLen := C.ulong(1024)
+	fieldBuf := [1024]C.uchar{}
+	capNamesLen := C.ulong(4096)
+	capNamesBuf := [4096]C.uchar{}
+	capValuesLen := C.ulong(4096)
+	capValuesBuf := [4096]C.uchar{}
+
+	n := C.context_get_result(ctx.c,
+		&uuidBuf[0], &uuidLen,
+		fieldC, &fieldBuf[0], &fieldLen,
+		&capNamesBuf[0], &capNamesLen,
+		&capValuesBuf[0], &capValuesLen)
+	if n < 0 {
+		return uuid.Nil, "", nil, fmt.Errorf("matched but failed to retrieve match result")
+	}
+
+	id, err := uuid.FromString(string(uuidBuf[:uuidLen]))
+	if err != nil {
+		return uuid.Nil, "", nil, fmt.Errorf("failed to parse matched uuid: %w", err)
+	}
+
+	var fieldValue string
+	if field != "" {
+		if fieldLen == 0 {
+			return uuid.Nil, "", nil, fmt.Errorf("no matched value for field %q", field)
+		}
+		fieldValue = string(fieldBuf[:fieldLen])
+	}
+
+	names := strings.Split(string(capNamesBuf[:capNamesLen]), "\n")
+	values := strings.Split(string(capValuesBuf[:capValuesLen]), "\n")
+	captures := make(map[string]string, n)
+	for i := 0; i < int(n) && i < len(names) && i < len(values); i++ {
+		captures[names[i]] = values[i]
+	}
+	return id, fieldValue, captures, nil
+}
+
+// The Context type holds the request-specific field values that a Router
+// is executed against, along with the result of that execution once
+// Router.Execute has been called.
+type Context struct {
+	c        *C.Context
+	schema   *Schema
+	matched  bool
+	captures map[string]string
+}
+
+// NewContext creates a new empty Context for populating values against s.
+// s is retained by the Rust core for the lifetime of the Context, which is
+// why s must be supplied up front rather than inferred per-field.
+func NewContext(s *Schema) *Context {
+	ctx := &Context{c: C.context_new(s.s), schema: s}
+	runtime.SetFinalizer(ctx, (*Context).Free)
+	return ctx
+}
+
+// The Free method deallocates a Context object
+// can be called manually or automatically by the GC.
+func (ctx *Context) Free() {
+	runtime.SetFinalizer(ctx, nil)
+	C.context_free(ctx.c)
+}
+
+// AddValue populates field with value by building a CValue tagged union in
+// place and handing it to context_add_value; there is no constructor or
+// destructor for CValue on the Rust side, it is a plain value type the
+// caller fills in. Supported Go types are string (String and Regex
+// fields), int/int64 (Int fields), net.IP (IpAddr fields), and *net.IPNet
+// (IpCidr fields).
+func (ctx *Context) AddValue(field string, value any) error {
+	if ctx.schema != nil {
+		field = ctx.schema.resolveAlias(field)
+	}
+
+	fieldC := C.CString(field)
+	defer C.free(unsafe.Pointer(fieldC))
+
+	var val C.CValue
+	switch v := value.(type) {
+	case string:
+		valueC := C.CString(v)
+		defer C.free(unsafe.Pointer(valueC))
+		val.tag = C.uint32_t(String)
+		val.str_value = (*C.schar)(valueC)
+	case int:
+		val.tag = C.uint32_t(Int)
+		val.int_value = C.int64_t(v)
+	case int64:
+		val.tag = C.uint32_t(Int)
+		val.int_value = C.int64_t(v)
+	case net.IP:
+		valueC := C.CString(v.String())
+		defer C.free(unsafe.Pointer(valueC))
+		val.tag = C.uint32_t(IpAddr)
+		val.str_value = (*C.schar)(valueC)
+	case *net.IPNet:
+		valueC := C.CString(v.String())
+		defer C.free(unsafe.Pointer(valueC))
+		val.tag = C.uint32_t(IpCidr)
+		val.str_value = (*C.schar)(valueC)
+	default:
+		return fmt.Errorf("unsupported value type %T for field %q", value, field)
+	}
+
+	errLen := C.ulong(1024)
+	errBuf := [1024]C.uchar{}
+	if !C.context_add_value(ctx.c, (*C.schar)(fieldC), &val, &errBuf[0], &errLen) {
+		return fmt.Errorf(string(errBuf[:errLen]))
+	}
 	return nil
 }
+
+// GetMatchedField returns the value matched for field by the most recent
+// successful Router.Execute on ctx. It returns an error if ctx has not
+// produced a match yet, or if the matched rule did not reference field.
+func (ctx *Context) GetMatchedField(field string) (string, error) {
+	if !ctx.matched {
+		return "", fmt.Errorf("no match recorded on this Context")
+	}
+	if ctx.schema != nil {
+		field = ctx.schema.resolveAlias(field)
+	}
+
+	_, value, _, err := contextGetResult(ctx, field)
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// GetMatchedCapture returns the value captured by the named regex capture
+// group after a successful Router.Execute.
+func (ctx *Context) GetMatchedCapture(name string) (string, error) {
+	v, ok := ctx.captures[name]
+	if !ok {
+		return "", fmt.Errorf("no matched capture named %q", name)
+	}
+	return v, nil
+}
+
+// reset clears ctx so it can be reused for another request against the same
+// Schema. context_reset has no return value: unlike AddValue/Execute, there
+// is no failure mode to report here.
+func (ctx *Context) reset() {
+	C.context_reset(ctx.c)
+	ctx.matched = false
+	ctx.captures = nil
+}
+
+// MatchResult is a pure-Go snapshot of the outcome of a Router.Match call,
+// safe to hold onto after the Context used to produce it has been returned
+// to the Router's internal pool.
+type MatchResult struct {
+	// Matched reports whether any Matcher matched.
+	Matched bool
+	// ID is the UUID of the matched Matcher. Only valid if Matched is true.
+	ID uuid.UUID
+	// Fields holds the matched value of every field passed to Match that
+	// participated in the match (fields supplied but not referenced by
+	// the matched rule are omitted). Only valid if Matched is true. Regex
+	// capture groups are not included here; use the lower-level
+	// NewContext/Execute/GetMatchedCapture API if captures are needed.
+	Fields map[string]string
+}
+
+// Match is a convenience wrapper around Execute that manages a Context
+// internally, acquiring one from an internal sync.Pool, populating it from
+// fields, executing the Router against it, and snapshotting the result into
+// a MatchResult before returning the Context to the pool. It is intended
+// for hot-path request dispatch, where allocating a fresh Context per
+// request would otherwise dominate cost. Router.Match is safe to call
+// concurrently from multiple goroutines.
+func (r *Router) Match(fields map[string]any) (MatchResult, error) {
+	var ctx *Context
+	if pooled := r.ctxPool.Get(); pooled != nil {
+		ctx = pooled.(*Context)
+	} else {
+		ctx = NewContext(r.schema)
+	}
+	defer func() {
+		ctx.reset()
+		r.ctxPool.Put(ctx)
+	}()
+
+	for field, value := range fields {
+		if err := ctx.AddValue(field, value); err != nil {
+			return MatchResult{}, err
+		}
+	}
+
+	matched, id, err := r.Execute(ctx)
+	if err != nil {
+		return MatchResult{}, err
+	}
+	if !matched {
+		return MatchResult{Matched: false}, nil
+	}
+
+	result := MatchResult{Matched: true, ID: id, Fields: make(map[string]string, len(fields))}
+	for field := range fields {
+		field = r.schema.resolveAlias(field)
+		if v, err := ctx.GetMatchedField(field); err == nil {
+			result.Fields[field] = v
+		}
+	}
+	return result, nil
+}
@@ -1,6 +1,8 @@
 package goatcrouter
 
 import (
+	"net"
+	"sync"
 	"testing"
 
 	"github.com/gofrs/uuid"
@@ -29,3 +31,206 @@ func Test_Verify(t *testing.T) {
 	require.NoError(t, verify("tcp.port == 1"))
 	require.Error(t, verify("bad.var == 9"))
 }
+
+func Test_Validate(t *testing.T) {
+	schema := NewSchema()
+	defer schema.Free()
+
+	schema.AddField("http.path", String)
+	schema.AddField("tcp.port", Int)
+
+	require.NoError(t, schema.Validate("tcp.port == 1"))
+
+	err := schema.Validate("tcp.port ==")
+	require.Error(t, err)
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, ParseErrorKind, verr.Kind)
+
+	err = schema.Validate("bad.var == 9")
+	require.Error(t, err)
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, FieldErrorKind, verr.Kind)
+}
+
+// Test_ClassifyValidationError pins down classifyValidationError's narrowed
+// phrase list, including the intentional false negative: a message that
+// merely mentions "type" without one of the known phrasings falls back to
+// ParseErrorKind rather than being guessed as field-related.
+func Test_ClassifyValidationError(t *testing.T) {
+	require.Equal(t, FieldErrorKind, classifyValidationError(`unknown field "bad.var"`))
+	require.Equal(t, FieldErrorKind, classifyValidationError("type mismatch for field tcp.port"))
+	require.Equal(t, ParseErrorKind, classifyValidationError("unexpected token at position 4"))
+	require.Equal(t, ParseErrorKind, classifyValidationError("did you mean to declare a new type here?"))
+}
+
+func Test_FieldAlias(t *testing.T) {
+	schema := NewSchema()
+	defer schema.Free()
+
+	schema.AddField("request.path", String)
+
+	require.Error(t, schema.AddFieldAlias("http.path", "request.other"))
+	require.Error(t, schema.AddFieldAlias("request.path", "request.path"))
+
+	require.NoError(t, schema.AddFieldAlias("http.path", "request.path"))
+
+	router := NewRouter(schema)
+	defer router.Free()
+
+	id, err := uuid.NewV4()
+	require.NoError(t, err)
+	require.NoError(t, router.AddMatcher(1, id, `http.path == "/foo"`))
+}
+
+func Test_Match(t *testing.T) {
+	schema := NewSchema()
+	defer schema.Free()
+
+	schema.AddField("http.path", String)
+	schema.AddField("tcp.port", Int)
+
+	router := NewRouter(schema)
+	defer router.Free()
+
+	id, err := uuid.NewV4()
+	require.NoError(t, err)
+	require.NoError(t, router.AddMatcher(1, id, `http.path == "/foo"`))
+
+	result, err := router.Match(map[string]any{"http.path": "/foo", "tcp.port": 8000})
+	require.NoError(t, err)
+	require.True(t, result.Matched)
+	require.Equal(t, id, result.ID)
+	require.Equal(t, "/foo", result.Fields["http.path"])
+
+	result, err = router.Match(map[string]any{"http.path": "/bar", "tcp.port": 8000})
+	require.NoError(t, err)
+	require.False(t, result.Matched)
+
+	// A third call reuses the pooled Context and must not see stale
+	// values left over from the earlier calls.
+	result, err = router.Match(map[string]any{"http.path": "/foo", "tcp.port": 9000})
+	require.NoError(t, err)
+	require.True(t, result.Matched)
+}
+
+func Test_AddValue_Types(t *testing.T) {
+	schema := NewSchema()
+	defer schema.Free()
+
+	schema.AddField("http.path", String)
+	schema.AddField("net.src.ip", IpAddr)
+	schema.AddField("net.src.cidr", IpCidr)
+
+	ctx := NewContext(schema)
+	defer ctx.Free()
+
+	require.NoError(t, ctx.AddValue("http.path", "/foo"))
+	require.NoError(t, ctx.AddValue("net.src.ip", net.ParseIP("10.0.0.1")))
+
+	_, ipnet, err := net.ParseCIDR("10.0.0.0/24")
+	require.NoError(t, err)
+	require.NoError(t, ctx.AddValue("net.src.cidr", ipnet))
+
+	require.Error(t, ctx.AddValue("http.path", 3.14))
+}
+
+// Test_GetMatchedField_Contract pins down the contract documented on
+// GetMatchedField: it must error before Execute has produced a match, after
+// a non-match, and for a field the matched rule never referenced, rather
+// than echoing back whatever was passed to AddValue.
+func Test_GetMatchedField_Contract(t *testing.T) {
+	schema := NewSchema()
+	defer schema.Free()
+
+	schema.AddField("http.path", String)
+	schema.AddField("tcp.port", Int)
+
+	router := NewRouter(schema)
+	defer router.Free()
+
+	id, err := uuid.NewV4()
+	require.NoError(t, err)
+	require.NoError(t, router.AddMatcher(1, id, `http.path == "/foo"`))
+
+	ctx := NewContext(schema)
+	defer ctx.Free()
+
+	require.NoError(t, ctx.AddValue("http.path", "/foo"))
+	_, err = ctx.GetMatchedField("http.path")
+	require.Error(t, err)
+
+	ctx.reset()
+	require.NoError(t, ctx.AddValue("http.path", "/bar"))
+	matched, _, err := router.Execute(ctx)
+	require.NoError(t, err)
+	require.False(t, matched)
+	_, err = ctx.GetMatchedField("http.path")
+	require.Error(t, err)
+
+	ctx.reset()
+	require.NoError(t, ctx.AddValue("http.path", "/foo"))
+	require.NoError(t, ctx.AddValue("tcp.port", 8000))
+	matched, _, err = router.Execute(ctx)
+	require.NoError(t, err)
+	require.True(t, matched)
+
+	v, err := ctx.GetMatchedField("http.path")
+	require.NoError(t, err)
+	require.Equal(t, "/foo", v)
+
+	_, err = ctx.GetMatchedField("tcp.port")
+	require.Error(t, err)
+}
+
+// Test_Match_FieldsOnlyParticipating asserts that MatchResult.Fields only
+// carries fields the matched rule actually referenced, matching its doc
+// comment rather than echoing back every field passed to Match.
+func Test_Match_FieldsOnlyParticipating(t *testing.T) {
+	schema := NewSchema()
+	defer schema.Free()
+
+	schema.AddField("http.path", String)
+	schema.AddField("tcp.port", Int)
+
+	router := NewRouter(schema)
+	defer router.Free()
+
+	id, err := uuid.NewV4()
+	require.NoError(t, err)
+	require.NoError(t, router.AddMatcher(1, id, `http.path == "/foo"`))
+
+	result, err := router.Match(map[string]any{"http.path": "/foo", "tcp.port": 8000})
+	require.NoError(t, err)
+	require.True(t, result.Matched)
+	require.Contains(t, result.Fields, "http.path")
+	require.NotContains(t, result.Fields, "tcp.port")
+}
+
+func Test_Match_Concurrent(t *testing.T) {
+	schema := NewSchema()
+	defer schema.Free()
+
+	schema.AddField("http.path", String)
+	schema.AddField("tcp.port", Int)
+
+	router := NewRouter(schema)
+	defer router.Free()
+
+	id, err := uuid.NewV4()
+	require.NoError(t, err)
+	require.NoError(t, router.AddMatcher(1, id, `http.path == "/foo"`))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			result, err := router.Match(map[string]any{"http.path": "/foo", "tcp.port": port})
+			require.NoError(t, err)
+			require.True(t, result.Matched)
+			require.Equal(t, id, result.ID)
+		}(i)
+	}
+	wg.Wait()
+}